@@ -8,6 +8,7 @@ import (
 	"io"
 	"microserviceArchWithGo/domain"
 	"net/http"
+	"sync"
 	"time"
 )
 
@@ -19,22 +20,44 @@ type GetProductResponse struct {
 	Product *domain.Product `json:"product"`
 }
 
+// BreakerSettings are the circuit breaker thresholds guarding the outbound
+// HTTP call. They're reconfigurable at runtime via UpdateConfig.
+type BreakerSettings struct {
+	MaxRequests  uint32
+	Interval     time.Duration
+	Timeout      time.Duration
+	FailureRatio float64
+}
+
 type GetProductHandler struct {
 	repository Repository
 	httpClient *retryablehttp.Client
-	breaker    *gobreaker.CircuitBreaker
-	httpServer string
+
+	mu              sync.RWMutex
+	httpServer      string
+	breaker         *gobreaker.CircuitBreaker
+	breakerSettings BreakerSettings
+}
+
+func NewGetProductHandler(repository Repository, httpClient *retryablehttp.Client, httpServer string, breakerSettings BreakerSettings) *GetProductHandler {
+	return &GetProductHandler{
+		repository:      repository,
+		httpClient:      httpClient,
+		httpServer:      httpServer,
+		breaker:         newCircuitBreaker(breakerSettings),
+		breakerSettings: breakerSettings,
+	}
 }
 
-func NewGetProductHandler(repository Repository, httpClient *retryablehttp.Client, httpServer string) *GetProductHandler {
-	breakerSettings := gobreaker.Settings{
+func newCircuitBreaker(settings BreakerSettings) *gobreaker.CircuitBreaker {
+	return gobreaker.NewCircuitBreaker(gobreaker.Settings{
 		Name:        "http-client",
-		MaxRequests: 3,
-		Interval:    5 * time.Second,
-		Timeout:     10 * time.Second,
+		MaxRequests: settings.MaxRequests,
+		Interval:    settings.Interval,
+		Timeout:     settings.Timeout,
 		ReadyToTrip: func(counts gobreaker.Counts) bool {
 			failureRation := float64(counts.TotalFailures) / float64(counts.Requests)
-			return counts.Requests >= 3 && failureRation >= 0.6
+			return counts.Requests >= 3 && failureRation >= settings.FailureRatio
 		},
 		OnStateChange: func(name string, from gobreaker.State, to gobreaker.State) {
 			zap.L().Info("Circuit breaker state changed",
@@ -42,18 +65,36 @@ func NewGetProductHandler(repository Repository, httpClient *retryablehttp.Clien
 				zap.String("from", from.String()),
 				zap.String("to", to.String()))
 		},
-	}
+	})
+}
 
-	return &GetProductHandler{
-		repository: repository,
-		httpClient: httpClient,
-		breaker:    gobreaker.NewCircuitBreaker(breakerSettings),
-		httpServer: httpServer,
+// UpdateConfig swaps in a new outbound server URL and circuit breaker
+// thresholds, so both can be changed without a restart. The breaker is only
+// rebuilt (resetting its state) when breakerSettings actually changed, so a
+// reload that only touches unrelated config doesn't silently close a
+// breaker that tripped for good reason.
+func (h *GetProductHandler) UpdateConfig(httpServer string, breakerSettings BreakerSettings) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.httpServer = httpServer
+
+	if breakerSettings != h.breakerSettings {
+		h.breaker = newCircuitBreaker(breakerSettings)
+		h.breakerSettings = breakerSettings
 	}
 }
 
+func (h *GetProductHandler) active() (string, *gobreaker.CircuitBreaker) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.httpServer, h.breaker
+}
+
 func (h *GetProductHandler) Handle(ctx context.Context, req *GetProductRequest) (*GetProductResponse, error) {
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, h.httpServer+"/random-error", nil)
+	httpServer, breaker := h.active()
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, httpServer+"/random-error", nil)
 
 	if err != nil {
 		return nil, err
@@ -64,7 +105,7 @@ func (h *GetProductHandler) Handle(ctx context.Context, req *GetProductRequest)
 		return nil, err
 	}
 
-	resp, err := h.breaker.Execute(func() (interface{}, error) {
+	resp, err := breaker.Execute(func() (interface{}, error) {
 		return h.httpClient.Do(retryableReq)
 	})
 
@@ -0,0 +1,116 @@
+package couchbase
+
+import (
+	"context"
+	"fmt"
+	"github.com/couchbase/gocb/v2"
+	vaultapi "github.com/hashicorp/vault/api"
+	"go.uber.org/zap"
+	"time"
+)
+
+// CredentialProvider supplies the authenticator used to (re)connect the
+// Couchbase cluster. Watch delivers the first credential synchronously and,
+// for implementations backed by a secrets engine with leased credentials,
+// keeps delivering fresh ones in the background as leases roll over.
+type CredentialProvider interface {
+	// Watch blocks until the first credential is ready, calls onRotate with
+	// it, and returns. Implementations that lease credentials continue
+	// calling onRotate in the background as each lease is renewed;
+	// implementations whose credentials never rotate return after the
+	// first call. An error means the first credential could not be
+	// obtained at all.
+	Watch(ctx context.Context, onRotate func(gocb.PasswordAuthenticator)) error
+}
+
+// StaticCredentials is today's behavior: a fixed username/password pulled
+// straight from AppConfig.
+type StaticCredentials struct {
+	Username string
+	Password string
+}
+
+func NewStaticCredentials(username, password string) *StaticCredentials {
+	return &StaticCredentials{Username: username, Password: password}
+}
+
+func (c *StaticCredentials) Watch(ctx context.Context, onRotate func(gocb.PasswordAuthenticator)) error {
+	onRotate(gocb.PasswordAuthenticator{Username: c.Username, Password: c.Password})
+	return nil
+}
+
+// VaultCredentials leases a dynamic Couchbase role from Vault's
+// database/creds/<role> endpoint, caching the lease and refreshing it on a
+// timer before it expires.
+type VaultCredentials struct {
+	client *vaultapi.Client
+	role   string
+}
+
+func NewVaultCredentials(client *vaultapi.Client, role string) *VaultCredentials {
+	return &VaultCredentials{client: client, role: role}
+}
+
+func (c *VaultCredentials) path() string {
+	return fmt.Sprintf("database/creds/%s", c.role)
+}
+
+func (c *VaultCredentials) lease(ctx context.Context) (gocb.PasswordAuthenticator, *vaultapi.Secret, error) {
+	secret, err := c.client.Logical().ReadWithContext(ctx, c.path())
+	if err != nil {
+		return gocb.PasswordAuthenticator{}, nil, fmt.Errorf("leasing vault role %q: %w", c.role, err)
+	}
+	if secret == nil {
+		return gocb.PasswordAuthenticator{}, nil, fmt.Errorf("leasing vault role %q: empty secret", c.role)
+	}
+
+	username, _ := secret.Data["username"].(string)
+	password, _ := secret.Data["password"].(string)
+
+	return gocb.PasswordAuthenticator{Username: username, Password: password}, secret, nil
+}
+
+// Watch leases the role once synchronously so the caller has a credential to
+// connect with immediately, then keeps leasing a fresh one in the
+// background on a timer set to roughly ⅔ of each lease's own TTL, handing
+// every lease (including the first) to onRotate.
+func (c *VaultCredentials) Watch(ctx context.Context, onRotate func(gocb.PasswordAuthenticator)) error {
+	auth, secret, err := c.lease(ctx)
+	if err != nil {
+		return fmt.Errorf("leasing initial couchbase credentials: %w", err)
+	}
+
+	zap.L().Info("leased couchbase credentials from vault",
+		zap.String("role", c.role), zap.String("leaseID", secret.LeaseID))
+	onRotate(auth)
+
+	go c.refreshLoop(ctx, time.Duration(secret.LeaseDuration)*time.Second*2/3, onRotate)
+
+	return nil
+}
+
+// refreshLoop waits out wait, then leases a fresh role and hands it to
+// onRotate, resetting wait to ~⅔ of that lease's own TTL. If Vault is
+// unreachable it backs off 30s and retries without disturbing the
+// currently active credential.
+func (c *VaultCredentials) refreshLoop(ctx context.Context, wait time.Duration, onRotate func(gocb.PasswordAuthenticator)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		auth, secret, err := c.lease(ctx)
+		if err != nil {
+			zap.L().Error("Failed to lease couchbase credentials from vault", zap.String("role", c.role), zap.Error(err))
+			wait = 30 * time.Second
+			continue
+		}
+
+		zap.L().Info("rotating couchbase credentials lease",
+			zap.String("role", c.role), zap.String("leaseID", secret.LeaseID))
+		onRotate(auth)
+		wait = time.Duration(secret.LeaseDuration) * time.Second * 2 / 3
+	}
+}
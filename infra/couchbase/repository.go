@@ -3,55 +3,118 @@ package couchbase
 import (
 	"context"
 	"errors"
+	"fmt"
 	gocbopentelemetry "github.com/couchbase/gocb-opentelemetry"
 	"github.com/couchbase/gocb/v2"
 	sdktrace "go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"microserviceArchWithGo/domain"
+	"sync"
 	"time"
 )
 
 type CouchBaseRepository struct {
+	couchbaseUrl string
+	tp           *sdktrace.TracerProvider
+	tracer       *gocbopentelemetry.OpenTelemetryRequestTracer
+
+	mu      sync.RWMutex
 	cluster *gocb.Cluster
 	bucket  *gocb.Bucket
-	tp      *sdktrace.TracerProvider
-	tracer  *gocbopentelemetry.OpenTelemetryRequestTracer
 }
 
-func NewCouchbaseRepository(tp *sdktrace.TracerProvider, couchbaseUrl string, username string, password string) *CouchBaseRepository {
+func NewCouchbaseRepository(tp *sdktrace.TracerProvider, couchbaseUrl string, credentials CredentialProvider) *CouchBaseRepository {
 	tracer := gocbopentelemetry.NewOpenTelemetryRequestTracer(tp)
-	cluster, err := gocb.Connect(couchbaseUrl, gocb.ClusterOptions{
+
+	r := &CouchBaseRepository{
+		couchbaseUrl: couchbaseUrl,
+		tp:           tp,
+		tracer:       tracer,
+	}
+
+	if err := credentials.Watch(context.Background(), r.rotate); err != nil {
+		zap.L().Fatal("Failed to obtain couchbase credentials", zap.Error(err))
+	}
+
+	return r
+}
+
+func (r *CouchBaseRepository) connect(auth gocb.PasswordAuthenticator) (*gocb.Cluster, *gocb.Bucket, error) {
+	cluster, err := gocb.Connect(r.couchbaseUrl, gocb.ClusterOptions{
 		TimeoutsConfig: gocb.TimeoutsConfig{
 			ConnectTimeout: 3 * time.Second,
 			KVTimeout:      3 * time.Second,
 			QueryTimeout:   3 * time.Second,
 		},
-		Authenticator: gocb.PasswordAuthenticator{
-			Username: username,
-			Password: password,
-		},
-		Transcoder: gocb.NewJSONTranscoder(),
-		Tracer:     tracer,
+		Authenticator: auth,
+		Transcoder:    gocb.NewJSONTranscoder(),
+		Tracer:        r.tracer,
 	})
 	if err != nil {
-		zap.L().Fatal("Failed to connect to couchbase", zap.Error(err))
+		return nil, nil, fmt.Errorf("connecting to couchbase: %w", err)
 	}
 
 	bucket := cluster.Bucket("products")
 	bucket.WaitUntilReady(3*time.Second, &gocb.WaitUntilReadyOptions{})
 
-	return &CouchBaseRepository{
-		cluster: cluster,
-		bucket:  bucket,
-		tracer:  tracer,
+	return cluster, bucket, nil
+}
+
+// rotate (re)authenticates against Couchbase with the given credential and
+// atomically swaps it in for the cluster/bucket pair that in-flight
+// requests are reading, then closes whatever cluster it replaced. It also
+// serves as the very first connect, in which case there is no stale
+// cluster to close.
+//
+// A failed reconnect only crashes the process when there is no existing
+// cluster to fall back on, i.e. the very first connect from
+// NewCouchbaseRepository. A failed reconnect during a later credential
+// rotation (a transient Vault/Couchbase blip) is logged and dropped instead,
+// leaving the current cluster/bucket in place so it keeps serving traffic.
+func (r *CouchBaseRepository) rotate(auth gocb.PasswordAuthenticator) {
+	cluster, bucket, err := r.connect(auth)
+	if err != nil {
+		r.mu.RLock()
+		hasExisting := r.cluster != nil
+		r.mu.RUnlock()
+
+		if !hasExisting {
+			zap.L().Fatal("Failed to connect to couchbase", zap.Error(err))
+		}
+
+		zap.L().Error("Failed to reconnect to couchbase during credential rotation; keeping existing connection", zap.Error(err))
+		return
+	}
+
+	r.mu.Lock()
+	stale := r.cluster
+	r.cluster = cluster
+	r.bucket = bucket
+	r.mu.Unlock()
+
+	if stale == nil {
+		zap.L().Info("connected to couchbase cluster")
+		return
+	}
+
+	zap.L().Info("rotated couchbase cluster credentials")
+
+	if err := stale.Close(&gocb.ClusterCloseOptions{}); err != nil {
+		zap.L().Warn("Failed to close stale couchbase cluster after credential rotation", zap.Error(err))
 	}
 }
 
+func (r *CouchBaseRepository) activeBucket() *gocb.Bucket {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.bucket
+}
+
 func (r *CouchBaseRepository) GetProduct(ctx context.Context, id string) (*domain.Product, error) {
 	ctx, span := r.tracer.Wrapped().Start(ctx, "GetProduct")
 	defer span.End()
 
-	data, err := r.bucket.DefaultCollection().Get(id, &gocb.GetOptions{
+	data, err := r.activeBucket().DefaultCollection().Get(id, &gocb.GetOptions{
 		Timeout:    3 * time.Second,
 		Context:    ctx,
 		ParentSpan: gocbopentelemetry.NewOpenTelemetryRequestSpan(ctx, span),
@@ -67,5 +130,28 @@ func (r *CouchBaseRepository) GetProduct(ctx context.Context, id string) (*domai
 	}
 
 	var product domain.Product
+	if err := data.Content(&product); err != nil {
+		zap.L().Error("Failed to decode product", zap.Error(err))
+		return nil, err
+	}
+
+	return &product, nil
+}
+
+func (r *CouchBaseRepository) CreateProduct(ctx context.Context, product *domain.Product) error {
+	ctx, span := r.tracer.Wrapped().Start(ctx, "CreateProduct")
+	defer span.End()
+
+	_, err := r.activeBucket().DefaultCollection().Upsert(product.ID, product, &gocb.UpsertOptions{
+		Timeout:    3 * time.Second,
+		Context:    ctx,
+		ParentSpan: gocbopentelemetry.NewOpenTelemetryRequestSpan(ctx, span),
+	})
+
+	if err != nil {
+		zap.L().Error("Failed to create product", zap.Error(err))
+		return err
+	}
 
+	return nil
 }
@@ -1,13 +1,18 @@
 package main
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/log"
 	"github.com/gofiber/fiber/v2/middleware/adaptor"
 	"github.com/hashicorp/go-retryablehttp"
+	vaultapi "github.com/hashicorp/vault/api"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.opentelemetry.io/otel"
@@ -23,10 +28,15 @@ import (
 	"microserviceArchWithGo/infra/couchbase"
 	"microserviceArchWithGo/pkg/config"
 	_ "microserviceArchWithGo/pkg/log"
+	"microserviceArchWithGo/pkg/telemetry"
+	"io"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"regexp"
+	"strconv"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
@@ -72,7 +82,8 @@ func handle[R Request, Res Response](handler HandlerInterface[R, Res]) fiber.Han
 }
 
 func main() {
-	appConfig := config.Read()
+	configLoader := config.NewLoader()
+	appConfig := configLoader.Load()
 	defer zap.L().Sync()
 
 	zap.L().Info("app starting...")
@@ -81,12 +92,12 @@ func main() {
 	tp := initTracer(appConfig)
 	client := httpc()
 
+	wait := newRetryWait(retryTimeouts(appConfig))
+
 	retryClient := retryablehttp.NewClient()
 	retryClient.HTTPClient.Transport = client.Transport
 	retryClient.RetryMax = 0
-	retryClient.RetryWaitMin = 100 * time.Millisecond
-	retryClient.RetryWaitMax = 10 * time.Second
-	retryClient.Backoff = retryablehttp.LinearJitterBackoff
+	retryClient.Backoff = wait.Backoff
 	retryClient.CheckRetry = func(ctx context.Context, resp *http.Response, err error) (bool, error) {
 		if ctx.Err() != nil {
 			return false, ctx.Err()
@@ -94,12 +105,19 @@ func main() {
 		return retryablehttp.DefaultRetryPolicy(ctx, resp, err)
 	}
 
-	couchBaseRepository := couchbase.NewCouchbaseRepository(tp, appConfig.CouchbaseUrl, appConfig.CouchbaseUsername, appConfig.CouchbasePassword)
+	couchBaseRepository := couchbase.NewCouchbaseRepository(tp, appConfig.CouchbaseUrl, couchbaseCredentials(appConfig))
 
-	getProductHandler := product.NewGetProductHandler(couchBaseRepository, retryClient, appConfig.HttpServer)
+	getProductHandler := product.NewGetProductHandler(couchBaseRepository, retryClient, appConfig.HttpServer, breakerSettings(appConfig))
 	createProductHandler := product.NewCreateProductHandler(couchBaseRepository)
 	healthCheckHandler := healthcheck.NewHealthCheckHandler()
 
+	configLoader.Watch(context.Background(), func(reloaded *config.AppConfig) {
+		zap.L().Info("config reloaded", zap.Any("appConfig", reloaded))
+
+		wait.set(retryTimeouts(reloaded))
+		getProductHandler.UpdateConfig(reloaded.HttpServer, breakerSettings(reloaded))
+	})
+
 	app := fiber.New(fiber.Config{
 		IdleTimeout:  5 * time.Second,
 		ReadTimeout:  3 * time.Second,
@@ -107,6 +125,14 @@ func main() {
 		Concurrency:  256 * 1024,
 	})
 
+	app.Use(telemetry.FiberMiddleware(telemetry.Config{
+		TracerProvider:       tp,
+		TraceRequestHeaders:  appConfig.TraceRequestHeaders,
+		TraceResponseHeaders: appConfig.TraceResponseHeaders,
+		IgnoredRoutes:        []string{"/metrics", "/healthcheck"},
+		CustomRoutePatterns:  customRoutePatterns(appConfig),
+	}))
+
 	app.Get("/metrics", adaptor.HTTPHandler(promhttp.Handler()))
 	app.Get("/healthcheck", handle[healthcheck.HealthCheckRequest, healthcheck.HealthCheckResponse](healthCheckHandler))
 
@@ -127,6 +153,108 @@ func main() {
 	gracefulShutdown(app)
 }
 
+// retryWait holds the outbound retry client's backoff bounds behind atomics
+// so a config-reload goroutine can update them while requests are
+// concurrently retrying in flight, without racing on retryablehttp.Client's
+// own RetryWaitMin/RetryWaitMax fields.
+type retryWait struct {
+	min atomic.Int64
+	max atomic.Int64
+}
+
+func newRetryWait(min, max time.Duration) *retryWait {
+	w := &retryWait{}
+	w.set(min, max)
+	return w
+}
+
+func (w *retryWait) set(min, max time.Duration) {
+	w.min.Store(int64(min))
+	w.max.Store(int64(max))
+}
+
+// Backoff is a retryablehttp.Backoff that ignores the min/max arguments
+// retryablehttp passes in (its own client fields, which this type replaces)
+// and reads the current bounds atomically instead.
+func (w *retryWait) Backoff(_, _ time.Duration, attemptNum int, resp *http.Response) time.Duration {
+	return retryablehttp.LinearJitterBackoff(time.Duration(w.min.Load()), time.Duration(w.max.Load()), attemptNum, resp)
+}
+
+// retryTimeouts returns appConfig's RetryWaitMin/RetryWaitMax, falling back
+// to the previous hardcoded defaults when either is left unset.
+func retryTimeouts(appConfig *config.AppConfig) (time.Duration, time.Duration) {
+	min := appConfig.RetryWaitMin
+	if min <= 0 {
+		min = 100 * time.Millisecond
+	}
+
+	max := appConfig.RetryWaitMax
+	if max <= 0 {
+		max = 10 * time.Second
+	}
+
+	return min, max
+}
+
+// breakerSettings returns appConfig's circuit breaker thresholds, falling
+// back to the previous hardcoded defaults for anything left unset.
+func breakerSettings(appConfig *config.AppConfig) product.BreakerSettings {
+	settings := product.BreakerSettings{
+		MaxRequests:  appConfig.BreakerMaxRequests,
+		Interval:     appConfig.BreakerInterval,
+		Timeout:      appConfig.BreakerTimeout,
+		FailureRatio: appConfig.BreakerFailureRatio,
+	}
+
+	if settings.MaxRequests == 0 {
+		settings.MaxRequests = 3
+	}
+	if settings.Interval <= 0 {
+		settings.Interval = 5 * time.Second
+	}
+	if settings.Timeout <= 0 {
+		settings.Timeout = 10 * time.Second
+	}
+	if settings.FailureRatio <= 0 {
+		settings.FailureRatio = 0.6
+	}
+
+	return settings
+}
+
+// customRoutePatterns compiles appConfig.CustomRoutePatterns for the
+// telemetry middleware, exiting the process on an invalid pattern.
+func customRoutePatterns(appConfig *config.AppConfig) []*regexp.Regexp {
+	patterns := make([]*regexp.Regexp, 0, len(appConfig.CustomRoutePatterns))
+
+	for _, pattern := range appConfig.CustomRoutePatterns {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			zap.L().Fatal("Failed to compile custom route pattern", zap.String("pattern", pattern), zap.Error(err))
+		}
+		patterns = append(patterns, compiled)
+	}
+
+	return patterns
+}
+
+// couchbaseCredentials picks the CredentialProvider for the Couchbase
+// repository based on appConfig.CouchbaseAuthMode: "vault" leases a dynamic
+// role that rotates on a timer, anything else falls back to the static
+// username/password pair.
+func couchbaseCredentials(appConfig *config.AppConfig) couchbase.CredentialProvider {
+	if appConfig.CouchbaseAuthMode != "vault" {
+		return couchbase.NewStaticCredentials(appConfig.CouchbaseUsername, appConfig.CouchbasePassword)
+	}
+
+	vaultClient, err := vaultapi.NewClient(vaultapi.DefaultConfig())
+	if err != nil {
+		zap.L().Fatal("Failed to create vault client", zap.Error(err))
+	}
+
+	return couchbase.NewVaultCredentials(vaultClient, appConfig.CouchbaseVaultRole)
+}
+
 func gracefulShutdown(app *fiber.App) {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGKILL)
@@ -191,13 +319,43 @@ func initTracer(appConfig *config.AppConfig) *sdktrace.TracerProvider {
 		"content-type": "application/json",
 	}
 
+	clientOpts := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(appConfig.OtelTraceEndpoint),
+		otlptracehttp.WithHeaders(headers),
+		otlptracehttp.WithRetry(otlptracehttp.RetryConfig{
+			Enabled:         true,
+			InitialInterval: 1 * time.Second,
+			MaxInterval:     30 * time.Second,
+			MaxElapsedTime:  1 * time.Minute,
+		}),
+	}
+
+	// otlptracehttp.WithHTTPClient takes over the exporter's own transport
+	// construction, so WithTLSClientConfig and WithCompression would be
+	// silently dropped if passed alongside it: TLS and gzip are composed
+	// directly into the transport given to WithHTTPClient below instead.
+	baseTransport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if appConfig.OtelTraceCAFile != "" {
+		tlsConfig, err := tlsConfigFromCAFile(appConfig.OtelTraceCAFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		baseTransport.TLSClientConfig = tlsConfig
+	} else {
+		clientOpts = append(clientOpts, otlptracehttp.WithInsecure())
+	}
+
+	clientOpts = append(clientOpts, otlptracehttp.WithHTTPClient(&http.Client{
+		Transport: &otlpTransport{
+			base:            baseTransport,
+			gzipCompression: appConfig.OtelTraceCompression,
+		},
+	}))
+
 	exporter, err := otlptrace.New(
 		context.Background(),
-		otlptracehttp.NewClient(
-			otlptracehttp.WithEndpoint(appConfig.OtelTraceEndpoint),
-			otlptracehttp.WithHeaders(headers),
-			otlptracehttp.WithInsecure(),
-		),
+		otlptracehttp.NewClient(clientOpts...),
 	)
 	if err != nil {
 		log.Fatal(err)
@@ -217,3 +375,98 @@ func initTracer(appConfig *config.AppConfig) *sdktrace.TracerProvider {
 	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
 	return tp
 }
+
+// tlsConfigFromCAFile builds a client TLS config trusting the CA at caFile,
+// so the exporter can talk to an in-cluster collector over TLS while
+// otlptracehttp.WithInsecure() keeps local docker-compose setups working without one.
+func tlsConfigFromCAFile(caFile string) (*tls.Config, error) {
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading otel trace CA file %s: %w", caFile, err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in otel trace CA file %s", caFile)
+	}
+
+	return &tls.Config{RootCAs: pool}, nil
+}
+
+// otlpTransport wraps a base RoundTripper and (1) gzip-compresses the
+// request body when gzipCompression is set, and (2) on a 429 or 503
+// response carrying a Retry-After header, sleeps until that time before
+// letting otlptracehttp's own retry policy make the next attempt.
+type otlpTransport struct {
+	base            http.RoundTripper
+	gzipCompression bool
+}
+
+func (t *otlpTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.gzipCompression && req.Body != nil {
+		if err := gzipRequestBody(req); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp == nil {
+		return resp, err
+	}
+
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return resp, nil
+	}
+
+	if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+		select {
+		case <-req.Context().Done():
+		case <-time.After(wait):
+		}
+	}
+
+	return resp, nil
+}
+
+// gzipRequestBody replaces req.Body with its gzip-compressed contents and
+// sets Content-Encoding accordingly.
+func gzipRequestBody(req *http.Request) error {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return fmt.Errorf("reading request body to compress: %w", err)
+	}
+	req.Body.Close()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(body); err != nil {
+		return fmt.Errorf("gzip-compressing request body: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("gzip-compressing request body: %w", err)
+	}
+
+	req.Body = io.NopCloser(&buf)
+	req.ContentLength = int64(buf.Len())
+	req.Header.Set("Content-Encoding", "gzip")
+
+	return nil
+}
+
+// parseRetryAfter accepts both forms the spec allows: a number of seconds
+// or an HTTP-date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+	"os"
+	"time"
+)
+
+// AppConfig holds every tunable the service reads at startup: the server
+// bind port, the outbound HTTP target and its retry/breaker thresholds,
+// OTEL collector settings, and Couchbase connection/auth settings.
+type AppConfig struct {
+	Port string `yaml:"port"`
+
+	HttpServer string `yaml:"httpServer"`
+
+	// RetryWaitMin and RetryWaitMax bound the outbound retry client's
+	// backoff between attempts against HttpServer. Zero means "use the
+	// built-in default".
+	RetryWaitMin time.Duration `yaml:"retryWaitMin"`
+	RetryWaitMax time.Duration `yaml:"retryWaitMax"`
+
+	// BreakerMaxRequests, BreakerInterval, BreakerTimeout and
+	// BreakerFailureRatio configure the circuit breaker guarding calls to
+	// HttpServer. Zero means "use the built-in default".
+	BreakerMaxRequests  uint32        `yaml:"breakerMaxRequests"`
+	BreakerInterval     time.Duration `yaml:"breakerInterval"`
+	BreakerTimeout      time.Duration `yaml:"breakerTimeout"`
+	BreakerFailureRatio float64       `yaml:"breakerFailureRatio"`
+
+	OtelTraceEndpoint    string `yaml:"otelTraceEndpoint"`
+	OtelTraceCompression bool   `yaml:"otelTraceCompression"`
+	OtelTraceCAFile      string `yaml:"otelTraceCaFile"`
+
+	CouchbaseUrl       string `yaml:"couchbaseUrl"`
+	CouchbaseUsername  string `yaml:"couchbaseUsername"`
+	CouchbasePassword  string `yaml:"couchbasePassword"`
+	CouchbaseAuthMode  string `yaml:"couchbaseAuthMode"`
+	CouchbaseVaultRole string `yaml:"couchbaseVaultRole"`
+
+	TraceRequestHeaders  []string `yaml:"traceRequestHeaders"`
+	TraceResponseHeaders []string `yaml:"traceResponseHeaders"`
+
+	// CustomRoutePatterns are additional regular expressions (beyond the
+	// telemetry middleware's built-in UUID/numeric-ID patterns) for
+	// collapsing high-cardinality path segments to "{custom}" in metric
+	// labels and span attributes.
+	CustomRoutePatterns []string `yaml:"customRoutePatterns"`
+}
+
+const defaultConfigFile = "config.yaml"
+
+// Read loads AppConfig from the default config file only, with no env or
+// flag overrides. Prefer NewLoader().Load() for a layered load.
+func Read() *AppConfig {
+	cfg, err := readFile(defaultConfigFile)
+	if err != nil {
+		zap.L().Fatal("Failed to read config file", zap.Error(err))
+	}
+	return cfg
+}
+
+func readFile(path string) (*AppConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &AppConfig{}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,305 @@
+package config
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"go.uber.org/zap"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// provider merges whatever it knows about into cfg. Providers are applied
+// in chain order, so a later provider overrides fields an earlier one set.
+type provider interface {
+	apply(cfg *AppConfig) error
+}
+
+// Loader assembles AppConfig from a chain of providers: config file, then
+// environment variables, then CLI flags, each one overriding the last.
+type Loader struct {
+	paths  []string
+	prefix string
+	args   []string
+}
+
+// LoaderOption customizes a Loader returned by NewLoader.
+type LoaderOption func(*Loader)
+
+// WithEnvPrefix sets the prefix environment variables must carry, e.g.
+// "APP_" so APP_COUCHBASE_URL maps onto AppConfig.CouchbaseUrl. Defaults to
+// "APP_".
+func WithEnvPrefix(prefix string) LoaderOption {
+	return func(l *Loader) { l.prefix = prefix }
+}
+
+// WithArgs overrides the argument slice CLI flags are parsed from (defaults
+// to os.Args[1:]); mainly useful in tests.
+func WithArgs(args []string) LoaderOption {
+	return func(l *Loader) { l.args = args }
+}
+
+// NewLoader builds a Loader that searches /etc/microservice, ./config and
+// $XDG_CONFIG_HOME/microservice, in that order, for the first config file it
+// finds, then layers environment variables prefixed "APP_" and matching CLI
+// flags like --couchbase-url on top.
+func NewLoader(opts ...LoaderOption) *Loader {
+	l := &Loader{
+		paths:  defaultPaths(),
+		prefix: "APP_",
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+func defaultPaths() []string {
+	paths := []string{"/etc/microservice", "./config"}
+
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		paths = append(paths, filepath.Join(xdg, "microservice"))
+	}
+
+	return paths
+}
+
+// Paths returns the directories, in search order, that Load looks in for a
+// config file.
+func (l *Loader) Paths() []string {
+	return l.paths
+}
+
+// Load resolves AppConfig from the file, env and flag providers, in that
+// override order, exiting the process if any provider fails.
+func (l *Loader) Load() *AppConfig {
+	cfg, err := l.load()
+	if err != nil {
+		zap.L().Fatal("Failed to load config", zap.Error(err))
+	}
+	return cfg
+}
+
+func (l *Loader) load() (*AppConfig, error) {
+	cfg := &AppConfig{}
+
+	providers := []provider{
+		fileProvider{paths: l.paths},
+		envProvider{prefix: l.prefix},
+		flagProvider{args: l.args},
+	}
+
+	for _, p := range providers {
+		if err := p.apply(cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	return cfg, nil
+}
+
+// Watch reloads the config on SIGHUP and passes the new value to onReload,
+// so retry-client timeouts, breaker thresholds and the outbound HttpServer
+// URL can be changed without a restart. The watch loop stops when ctx is
+// cancelled.
+func (l *Loader) Watch(ctx context.Context, onReload func(*AppConfig)) {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(sigChan)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sigChan:
+				cfg, err := l.load()
+				if err != nil {
+					zap.L().Error("Failed to reload config on SIGHUP", zap.Error(err))
+					continue
+				}
+				zap.L().Info("Reloaded config on SIGHUP")
+				onReload(cfg)
+			}
+		}
+	}()
+}
+
+type fileProvider struct {
+	paths []string
+}
+
+func (p fileProvider) apply(cfg *AppConfig) error {
+	for _, dir := range p.paths {
+		for _, name := range []string{"config.yaml", "config.yml", "config.json"} {
+			path := filepath.Join(dir, name)
+			if _, err := os.Stat(path); err != nil {
+				continue
+			}
+
+			loaded, err := readFile(path)
+			if err != nil {
+				return fmt.Errorf("reading config file %s: %w", path, err)
+			}
+
+			*cfg = *loaded
+			return nil
+		}
+	}
+
+	return nil
+}
+
+type envProvider struct {
+	prefix string
+}
+
+func (p envProvider) apply(cfg *AppConfig) error {
+	lookup := func(name string) (string, bool) {
+		return os.LookupEnv(p.prefix + name)
+	}
+
+	if v, ok := lookup("PORT"); ok {
+		cfg.Port = v
+	}
+	if v, ok := lookup("HTTP_SERVER"); ok {
+		cfg.HttpServer = v
+	}
+	if v, ok := lookup("RETRY_WAIT_MIN"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("parsing %sRETRY_WAIT_MIN: %w", p.prefix, err)
+		}
+		cfg.RetryWaitMin = d
+	}
+	if v, ok := lookup("RETRY_WAIT_MAX"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("parsing %sRETRY_WAIT_MAX: %w", p.prefix, err)
+		}
+		cfg.RetryWaitMax = d
+	}
+	if v, ok := lookup("BREAKER_MAX_REQUESTS"); ok {
+		n, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return fmt.Errorf("parsing %sBREAKER_MAX_REQUESTS: %w", p.prefix, err)
+		}
+		cfg.BreakerMaxRequests = uint32(n)
+	}
+	if v, ok := lookup("BREAKER_INTERVAL"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("parsing %sBREAKER_INTERVAL: %w", p.prefix, err)
+		}
+		cfg.BreakerInterval = d
+	}
+	if v, ok := lookup("BREAKER_TIMEOUT"); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("parsing %sBREAKER_TIMEOUT: %w", p.prefix, err)
+		}
+		cfg.BreakerTimeout = d
+	}
+	if v, ok := lookup("BREAKER_FAILURE_RATIO"); ok {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("parsing %sBREAKER_FAILURE_RATIO: %w", p.prefix, err)
+		}
+		cfg.BreakerFailureRatio = f
+	}
+	if v, ok := lookup("OTEL_TRACE_ENDPOINT"); ok {
+		cfg.OtelTraceEndpoint = v
+	}
+	if v, ok := lookup("OTEL_TRACE_COMPRESSION"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("parsing %sOTEL_TRACE_COMPRESSION: %w", p.prefix, err)
+		}
+		cfg.OtelTraceCompression = b
+	}
+	if v, ok := lookup("OTEL_TRACE_CA_FILE"); ok {
+		cfg.OtelTraceCAFile = v
+	}
+	if v, ok := lookup("COUCHBASE_URL"); ok {
+		cfg.CouchbaseUrl = v
+	}
+	if v, ok := lookup("COUCHBASE_USERNAME"); ok {
+		cfg.CouchbaseUsername = v
+	}
+	if v, ok := lookup("COUCHBASE_PASSWORD"); ok {
+		cfg.CouchbasePassword = v
+	}
+	if v, ok := lookup("COUCHBASE_AUTH_MODE"); ok {
+		cfg.CouchbaseAuthMode = v
+	}
+	if v, ok := lookup("COUCHBASE_VAULT_ROLE"); ok {
+		cfg.CouchbaseVaultRole = v
+	}
+	if v, ok := lookup("TRACE_REQUEST_HEADERS"); ok {
+		cfg.TraceRequestHeaders = strings.Split(v, ",")
+	}
+	if v, ok := lookup("TRACE_RESPONSE_HEADERS"); ok {
+		cfg.TraceResponseHeaders = strings.Split(v, ",")
+	}
+	if v, ok := lookup("CUSTOM_ROUTE_PATTERNS"); ok {
+		cfg.CustomRoutePatterns = strings.Split(v, ",")
+	}
+
+	return nil
+}
+
+type flagProvider struct {
+	args []string
+}
+
+func (p flagProvider) apply(cfg *AppConfig) error {
+	args := p.args
+	if args == nil {
+		args = os.Args[1:]
+	}
+
+	fs := flag.NewFlagSet("microservice", flag.ContinueOnError)
+
+	port := fs.String("port", "", "HTTP server port")
+	httpServer := fs.String("http-server", "", "outbound HTTP server URL")
+	retryWaitMin := fs.Duration("retry-wait-min", 0, "outbound retry client minimum backoff")
+	retryWaitMax := fs.Duration("retry-wait-max", 0, "outbound retry client maximum backoff")
+	otelTraceEndpoint := fs.String("otel-trace-endpoint", "", "OTLP trace collector endpoint")
+	couchbaseUrl := fs.String("couchbase-url", "", "Couchbase connection string")
+	couchbaseAuthMode := fs.String("couchbase-auth-mode", "", "Couchbase auth mode: static|vault")
+
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("parsing flags: %w", err)
+	}
+
+	if *port != "" {
+		cfg.Port = *port
+	}
+	if *httpServer != "" {
+		cfg.HttpServer = *httpServer
+	}
+	if *retryWaitMin != 0 {
+		cfg.RetryWaitMin = *retryWaitMin
+	}
+	if *retryWaitMax != 0 {
+		cfg.RetryWaitMax = *retryWaitMax
+	}
+	if *otelTraceEndpoint != "" {
+		cfg.OtelTraceEndpoint = *otelTraceEndpoint
+	}
+	if *couchbaseUrl != "" {
+		cfg.CouchbaseUrl = *couchbaseUrl
+	}
+	if *couchbaseAuthMode != "" {
+		cfg.CouchbaseAuthMode = *couchbaseAuthMode
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package normalize
+
+import "regexp"
+
+var (
+	uuidPattern    = regexp.MustCompile(`[0-9a-fA-F]{8}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{12}`)
+	numericPattern = regexp.MustCompile(`/(\d+)`)
+)
+
+// replacement pairs a compiled pattern with the placeholder that replaces
+// each match, applied in order.
+type replacement struct {
+	pattern     *regexp.Regexp
+	placeholder string
+}
+
+// URLPatterns normalises request paths so high-cardinality segments (UUIDs,
+// numeric IDs, or caller-supplied patterns) collapse to a stable
+// placeholder before being used as a metric label or span attribute.
+type URLPatterns struct {
+	replacements []replacement
+}
+
+// NewURLPatterns builds an URLPatterns that recognises UUIDs and numeric IDs
+// out of the box, plus any additional patterns supplied by the caller
+// (typically loaded from config) mapped to the "{custom}" placeholder.
+func NewURLPatterns(custom []*regexp.Regexp) *URLPatterns {
+	replacements := []replacement{
+		{pattern: uuidPattern, placeholder: "{uuid}"},
+		{pattern: numericPattern, placeholder: "/{id}"},
+	}
+
+	for _, pattern := range custom {
+		replacements = append(replacements, replacement{pattern: pattern, placeholder: "{custom}"})
+	}
+
+	return &URLPatterns{replacements: replacements}
+}
+
+// Normalise replaces each recognised high-cardinality segment of path with
+// its placeholder. Query strings are left untouched by callers that strip
+// them first; Normalise itself only rewrites what its patterns match.
+func (p *URLPatterns) Normalise(path string) string {
+	for _, r := range p.replacements {
+		path = r.pattern.ReplaceAllString(path, r.placeholder)
+	}
+	return path
+}
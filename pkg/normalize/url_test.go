@@ -0,0 +1,37 @@
+package normalize
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestURLPatterns_Normalise(t *testing.T) {
+	patterns := NewURLPatterns(nil)
+
+	tests := map[string]string{
+		"/products/550e8400-e29b-41d4-a716-446655440000":           "/products/{uuid}",
+		"/products/550e8400e29b41d4a716446655440000":               "/products/{uuid}",
+		"/products/42":                                             "/products/{id}",
+		"/products/42/reviews/7":                                   "/products/{id}/reviews/{id}",
+		"/products/550e8400-e29b-41d4-a716-446655440000/reviews/7": "/products/{uuid}/reviews/{id}",
+		"/products/42?include=reviews&limit=10":                    "/products/{id}?include=reviews&limit=10",
+		"/healthcheck": "/healthcheck",
+	}
+
+	for input, expected := range tests {
+		if got := patterns.Normalise(input); got != expected {
+			t.Errorf("Normalise(%q) = %q, want %q", input, got, expected)
+		}
+	}
+}
+
+func TestURLPatterns_Normalise_Custom(t *testing.T) {
+	patterns := NewURLPatterns([]*regexp.Regexp{regexp.MustCompile(`sku-[A-Z0-9]+`)})
+
+	got := patterns.Normalise("/products/sku-AB12C/details")
+	want := "/products/{custom}/details"
+
+	if got != want {
+		t.Errorf("Normalise() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,181 @@
+package telemetry
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"microserviceArchWithGo/pkg/normalize"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Config controls how FiberMiddleware instruments incoming requests.
+type Config struct {
+	// TracerProvider is used to start a server span for every request that
+	// is not in IgnoredRoutes.
+	TracerProvider *sdktrace.TracerProvider
+
+	// TraceRequestHeaders and TraceResponseHeaders are whitelists of header
+	// names that get copied into the span as attributes (prefixed with
+	// "http.request.header." / "http.response.header.").
+	TraceRequestHeaders  []string
+	TraceResponseHeaders []string
+
+	// IgnoredRoutes are skipped entirely: no span, no metric.
+	IgnoredRoutes []string
+
+	// CustomRoutePatterns are additional high-cardinality path segments
+	// (beyond the built-in UUID/numeric-ID patterns) to collapse to
+	// "{custom}" when falling back to path normalisation.
+	CustomRoutePatterns []*regexp.Regexp
+}
+
+var requestDuration = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "http_server_request_duration_seconds",
+		Help:    "Latency of HTTP requests handled by the server, by route and status code.",
+		Buckets: prometheus.DefBuckets,
+	},
+	[]string{"route", "method", "status_code"},
+)
+
+func init() {
+	prometheus.MustRegister(requestDuration)
+}
+
+// FiberMiddleware returns a fiber.Handler that starts a server span for every
+// incoming request, records request/response metadata on it, emits a
+// Prometheus latency histogram per route and status, and recovers panics by
+// attaching them to the active span before returning a 500.
+func FiberMiddleware(cfg Config) fiber.Handler {
+	ignored := make(map[string]struct{}, len(cfg.IgnoredRoutes))
+	for _, route := range cfg.IgnoredRoutes {
+		ignored[route] = struct{}{}
+	}
+
+	tracer := cfg.TracerProvider.Tracer("microserviceArchWithGo/pkg/telemetry")
+	patterns := normalize.NewURLPatterns(cfg.CustomRoutePatterns)
+
+	return func(c *fiber.Ctx) error {
+		// IgnoredRoutes is matched against the raw request path rather than
+		// the resolved route label below: c.Route() only reflects the
+		// deepest matched route once c.Next() has walked the rest of the
+		// stack, so it's still this middleware's own "/" route at this
+		// point. The ignored entries ("/metrics", "/healthcheck") are
+		// static paths, so matching on c.Path() here is equivalent and
+		// lets us skip tracing/metrics for them entirely.
+		if _, skip := ignored[c.Path()]; skip {
+			return c.Next()
+		}
+
+		ctx, span := tracer.Start(c.UserContext(), c.Path(), trace.WithSpanKind(trace.SpanKindServer))
+		defer span.End()
+
+		// This middleware's own route, which c.Route() still returns after
+		// c.Next() when no registered route matched the request.
+		own := c.Route()
+
+		span.SetAttributes(
+			attribute.String("http.method", c.Method()),
+			attribute.Int64("http.request_content_length", int64(len(c.Request().Body()))),
+		)
+
+		for _, header := range cfg.TraceRequestHeaders {
+			if value := c.Get(header); value != "" {
+				span.SetAttributes(attribute.String("http.request.header."+header, value))
+			}
+		}
+
+		c.SetUserContext(ctx)
+
+		start := time.Now()
+		err := recoverAndHandle(c, span)
+		duration := time.Since(start).Seconds()
+
+		// Only now, after c.Next() has run, has Fiber resolved the deepest
+		// matched route, so the route label is read here rather than
+		// before recoverAndHandle.
+		route := routeLabel(c, own, patterns)
+		span.SetName(route)
+
+		// A returned error (such as Fiber's 404 for an unmatched route) only
+		// becomes the response status once the app's ErrorHandler runs after
+		// this middleware, so its status is taken from the error itself.
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				status = fiberErr.Code
+			}
+		}
+		span.SetAttributes(
+			attribute.String("http.route", route),
+			attribute.Int("http.status_code", status),
+			attribute.Int64("http.response_content_length", int64(len(c.Response().Body()))),
+		)
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, "server error")
+		}
+
+		for _, header := range cfg.TraceResponseHeaders {
+			if value := string(c.Response().Header.Peek(header)); value != "" {
+				span.SetAttributes(attribute.String("http.response.header."+header, value))
+			}
+		}
+
+		requestDuration.WithLabelValues(route, c.Method(), strconv.Itoa(status)).Observe(duration)
+
+		return err
+	}
+}
+
+// routeLabel picks the value used for the http.route span attribute and the
+// Prometheus route label. It must be called after c.Next() has run: Fiber
+// only updates c.Route() to the deepest matched route (e.g. "/products/:id")
+// as the chain is walked, so reading it any earlier always yields this
+// middleware's own "/" route. Requests Fiber couldn't match to a registered
+// route, for which c.Route() is still own, fall back to the normalised
+// request path.
+func routeLabel(c *fiber.Ctx, own *fiber.Route, patterns *normalize.URLPatterns) string {
+	if route := c.Route(); route != nil && route != own && route.Path != "" {
+		return route.Path
+	}
+	return patterns.Normalise(c.Path())
+}
+
+// recoverAndHandle runs the rest of the chain, turning a panic into a
+// recorded span error, a zap log line and a 500 response instead of letting
+// it crash the server.
+func recoverAndHandle(c *fiber.Ctx, span trace.Span) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			span.RecordError(panicToError(r))
+			span.SetStatus(codes.Error, "panic recovered")
+			zap.L().Error("Recovered from panic", zap.Any("panic", r))
+			err = c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "internal server error"})
+		}
+	}()
+
+	return c.Next()
+}
+
+func panicToError(r interface{}) error {
+	if err, ok := r.(error); ok {
+		return err
+	}
+	return fiber.NewError(fiber.StatusInternalServerError, "panic: "+fiberSprint(r))
+}
+
+func fiberSprint(r interface{}) string {
+	if s, ok := r.(string); ok {
+		return s
+	}
+	return "unknown panic"
+}
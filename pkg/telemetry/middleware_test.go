@@ -0,0 +1,58 @@
+package telemetry
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// observations returns how many requests requestDuration has recorded under
+// the given route, method and status code labels.
+func observations(t *testing.T, labels ...string) uint64 {
+	t.Helper()
+	var m dto.Metric
+	if err := requestDuration.WithLabelValues(labels...).(prometheus.Histogram).Write(&m); err != nil {
+		t.Fatalf("reading histogram: %v", err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+// TestFiberMiddleware_UnmatchedRouteFallsBackToNormalisedPath guards against
+// the routeLabel fallback going dead again: it must only ever be exercised
+// for requests Fiber couldn't match to a registered route, which requires
+// reading c.Route() after c.Next() has resolved it.
+func TestFiberMiddleware_UnmatchedRouteFallsBackToNormalisedPath(t *testing.T) {
+	tp := sdktrace.NewTracerProvider()
+	defer tp.Shutdown(context.Background())
+
+	app := fiber.New()
+	app.Use(FiberMiddleware(Config{TracerProvider: tp}))
+	app.Get("/products/:id", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+	req := httptest.NewRequest(fiber.MethodGet, "/products/42", nil)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+	resp.Body.Close()
+
+	if got := observations(t, "/products/:id", fiber.MethodGet, "200"); got == 0 {
+		t.Errorf("matched route should be labelled with its route template, got no observation")
+	}
+
+	req = httptest.NewRequest(fiber.MethodGet, "/nonexistent/42", nil)
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+	resp.Body.Close()
+
+	if got := observations(t, "/nonexistent/{id}", fiber.MethodGet, "404"); got == 0 {
+		t.Errorf("unmatched route should fall back to the normalised path, got no observation")
+	}
+}